@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Endpoint registers a single Hubspot listing to back up. Adding support for
+// a new endpoint is a matter of appending one of these, rather than writing
+// a new backupX function.
+type Endpoint struct {
+	URL          string
+	Name         string
+	Paginator    Paginator
+	Associations []string
+	Properties   []string
+
+	// OnItem, if set, runs for every item backed up from this endpoint, in
+	// addition to the usual JSON storage. Used by the files endpoint to
+	// fetch binary content, and by content endpoints to pull in any
+	// Hubspot-hosted assets they reference.
+	OnItem func(run *backupRun, item map[string]interface{})
+}
+
+// queryURL builds the full request URL for the page at offset, including
+// the CRM v3 associations/properties params when the endpoint asks for them.
+func (e Endpoint) queryURL(offset string) string {
+	query := e.Paginator.Query(offset)
+	if len(e.Associations) > 0 {
+		query += "&associations=" + strings.Join(e.Associations, ",")
+	}
+	if len(e.Properties) > 0 {
+		query += "&properties=" + strings.Join(e.Properties, ",")
+	}
+	return e.URL + "?" + query
+}
+
+// staticEndpoints are every endpoint known ahead of time: the legacy v1/v2
+// listings this tool has always backed up, plus the CRM v3 objects.
+// Portal-specific custom objects are discovered at runtime; see
+// discoverCustomObjectEndpoints.
+var staticEndpoints = []Endpoint{
+	{URL: "https://api.hubapi.com/contacts/v1/lists", Name: "lists", Paginator: hasMoreOffsetPaginator{}},
+	{URL: "https://api.hubapi.com/content/api/v2/blogs", Name: "blogs", Paginator: oncePaginator{}},
+	{URL: "https://api.hubapi.com/content/api/v2/blog-posts", Name: "blog-posts", Paginator: limitOffsetPaginator{}, OnItem: downloadReferencedFiles},
+	{URL: "https://api.hubapi.com/blogs/v3/blog-authors", Name: "blog-authors", Paginator: limitOffsetPaginator{}},
+	{URL: "https://api.hubapi.com/blogs/v3/topics", Name: "blog-topics", Paginator: limitOffsetPaginator{}},
+	{URL: "https://api.hubapi.com/comments/v3/comments", Name: "blog-comments", Paginator: limitOffsetPaginator{}},
+	{URL: "https://api.hubapi.com/content/api/v2/layouts", Name: "layouts", Paginator: limitOffsetPaginator{}},
+	{URL: "https://api.hubapi.com/content/api/v2/pages", Name: "pages", Paginator: limitOffsetPaginator{}, OnItem: downloadReferencedFiles},
+	{URL: "https://api.hubapi.com/hubdb/api/v2/tables", Name: "hubdb-tables", Paginator: oncePaginator{}},
+	{URL: "https://api.hubapi.com/content/api/v2/templates", Name: "templates", Paginator: limitOffsetPaginator{}},
+	{URL: "https://api.hubapi.com/url-mappings/v3/url-mappings", Name: "url-mappings", Paginator: limitOffsetPaginator{}},
+	{URL: "https://api.hubapi.com/deals/v1/deal/paged", Name: "deals", Paginator: hasMoreOffsetPaginator{}},
+	{URL: "https://api.hubapi.com/marketing-emails/v1/emails", Name: "marketing-emails", Paginator: limitOffsetPaginator{}, OnItem: downloadReferencedFiles},
+	{URL: "https://api.hubapi.com/automation/v3/workflows", Name: "workflows", Paginator: oncePaginator{}},
+	{URL: "https://api.hubapi.com/companies/v2/companies/paged", Name: "companies", Paginator: hasMoreOffsetPaginator{}},
+	{URL: "https://api.hubapi.com/contacts/v1/lists/all/contacts/all", Name: "contacts", Paginator: vidOffsetPaginator{}},
+	// forms omitted until its typeArray nesting is sorted out (see original TODO)
+
+	// CRM v3 objects.
+	{URL: "https://api.hubapi.com/crm/v3/objects/tickets", Name: "tickets", Paginator: cursorPaginator{}, Associations: []string{"contacts", "companies", "deals"}},
+	{URL: "https://api.hubapi.com/crm/v3/objects/products", Name: "products", Paginator: cursorPaginator{}},
+	{URL: "https://api.hubapi.com/crm/v3/objects/line_items", Name: "line-items", Paginator: cursorPaginator{}, Associations: []string{"deals"}},
+	{URL: "https://api.hubapi.com/crm/v3/objects/quotes", Name: "quotes", Paginator: cursorPaginator{}, Associations: []string{"deals", "line_items"}},
+	{URL: "https://api.hubapi.com/crm/v3/objects/notes", Name: "notes", Paginator: cursorPaginator{}, Associations: []string{"contacts", "companies", "deals"}},
+	{URL: "https://api.hubapi.com/crm/v3/objects/calls", Name: "calls", Paginator: cursorPaginator{}, Associations: []string{"contacts", "companies", "deals"}},
+	{URL: "https://api.hubapi.com/crm/v3/objects/meetings", Name: "meetings", Paginator: cursorPaginator{}, Associations: []string{"contacts", "companies", "deals"}},
+	{URL: "https://api.hubapi.com/crm/v3/objects/tasks", Name: "tasks", Paginator: cursorPaginator{}, Associations: []string{"contacts", "companies", "deals"}},
+	{URL: "https://api.hubapi.com/crm/v3/objects/emails", Name: "email-engagements", Paginator: cursorPaginator{}, Associations: []string{"contacts", "companies", "deals"}},
+	{URL: "https://api.hubapi.com/crm/v3/owners", Name: "owners", Paginator: cursorPaginator{}},
+	{URL: "https://api.hubapi.com/crm/v3/pipelines/deals", Name: "deal-pipelines", Paginator: oncePaginator{}},
+	{URL: "https://api.hubapi.com/crm/v3/pipelines/tickets", Name: "ticket-pipelines", Paginator: oncePaginator{}},
+	{URL: "https://api.hubapi.com/crm/v3/properties/contacts", Name: "contact-properties", Paginator: oncePaginator{}},
+	{URL: "https://api.hubapi.com/crm/v3/properties/companies", Name: "company-properties", Paginator: oncePaginator{}},
+	{URL: "https://api.hubapi.com/crm/v3/properties/deals", Name: "deal-properties", Paginator: oncePaginator{}},
+
+	// File Manager assets: metadata here, binary content fetched by OnItem.
+	{URL: "https://api.hubapi.com/files/v3/files", Name: "files", Paginator: cursorPaginator{}, OnItem: downloadFileAsset},
+}
+
+// discoverCustomObjectEndpoints asks /crm/v3/schemas for the portal's custom
+// object types, since unlike everything in staticEndpoints these can't be
+// known ahead of time. Failures are non-fatal: a portal with no custom
+// objects (or an older account without v3 schema access) just backs up
+// nothing extra.
+func discoverCustomObjectEndpoints(run *backupRun) []Endpoint {
+	resp, err := executeRequest(run.hubspotConfig, run.limiter, "crm-schemas", "https://api.hubapi.com/crm/v3/schemas")
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode > 299 {
+		return nil
+	}
+
+	var schemas struct {
+		Results []struct {
+			Name         string `json:"name"`
+			ObjectTypeID string `json:"objectTypeId"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &schemas); err != nil {
+		return nil
+	}
+
+	endpoints := make([]Endpoint, 0, len(schemas.Results))
+	for _, schema := range schemas.Results {
+		endpoints = append(endpoints, Endpoint{
+			URL:       "https://api.hubapi.com/crm/v3/objects/" + schema.ObjectTypeID,
+			Name:      "custom-" + schema.Name,
+			Paginator: cursorPaginator{},
+		})
+	}
+	return endpoints
+}
+
+// backupEndpoint pages through a single Endpoint to completion, resubmitting
+// itself to run's pool for each subsequent page until its Paginator reports
+// there's nothing left.
+func backupEndpoint(run *backupRun, ep Endpoint, offset string) {
+	var error Error
+	var results map[string]interface{}
+	run.startEndpoint(ep.Name)
+
+	resp, err := executeRequest(run.hubspotConfig, run.limiter, ep.Name, ep.queryURL(offset))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode > 299 {
+		json.Unmarshal(body, &error)
+		recordError(ep.Name, fmt.Sprintf("http_%d", resp.StatusCode))
+		run.progress.Error(ep.Name, fmt.Sprintf("%v %v: %v", resp.StatusCode, http.StatusText(resp.StatusCode), error.Message))
+		return
+	}
+
+	if err := json.Unmarshal(body, &results); err != nil {
+		panic(err)
+	}
+
+	typeArray := extractItems(results, ep.Name)
+	if len(typeArray) == 0 {
+		run.finishEndpoint(ep.Name, "complete")
+		return
+	}
+
+	recordItems(ep.Name, len(typeArray))
+	run.progress.Update(ep.Name, offsetForDisplay(offset), len(typeArray))
+
+	storeItems(run.sink, run.manifest, run.mu, ep.Name, offset, typeArray)
+
+	if ep.OnItem != nil {
+		items := make([]map[string]interface{}, 0, len(typeArray))
+		for _, raw := range typeArray {
+			if item, ok := raw.(map[string]interface{}); ok {
+				items = append(items, item)
+			}
+		}
+		if len(items) > 0 {
+			run.submit(func() {
+				for _, item := range items {
+					ep.OnItem(run, item)
+				}
+			})
+		}
+	}
+
+	if next, more := ep.Paginator.Next(offset, results, len(typeArray)); more {
+		run.submit(func() { backupEndpoint(run, ep, next) })
+	} else {
+		run.finishEndpoint(ep.Name, "complete")
+	}
+}
+
+// extractItems finds the list of items in a Hubspot response body: CRM v3
+// endpoints nest them under "results", most v1/v2 endpoints under "objects",
+// and the rest under a field named after the endpoint itself.
+func extractItems(results map[string]interface{}, endpoint string) []interface{} {
+	if v, ok := results["results"].([]interface{}); ok {
+		return v
+	}
+	if v, ok := results["objects"].([]interface{}); ok {
+		return v
+	}
+	if v, ok := results[endpoint].([]interface{}); ok {
+		return v
+	}
+	return nil
+}
+
+// offsetForDisplay renders a Paginator offset/cursor back to an int for
+// progress reporting. Cursor-based pagination has no meaningful integer
+// offset, so it just reports 0.
+func offsetForDisplay(offset string) int {
+	n, err := strconv.Atoi(offset)
+	if err != nil {
+		return 0
+	}
+	return n
+}
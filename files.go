@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// cdnHostMarkers are substrings found in URLs Hubspot serves binary File
+// Manager assets from. Content endpoints (blog posts, pages, marketing
+// emails) reference assets by URL rather than by file ID, so this is how
+// downloadReferencedFiles recognizes them.
+var cdnHostMarkers = []string{"hubspotusercontent", "hs-sites.com", "cdn2.hubspot.net", ".hubspot.net"}
+
+// downloadFileAsset is the files endpoint's OnItem hook: it downloads the
+// binary content for a single File Manager entry.
+func downloadFileAsset(run *backupRun, item map[string]interface{}) {
+	id := fmt.Sprintf("%v", item["id"])
+	name, _ := item["name"].(string)
+	if name == "" {
+		name = id
+	}
+	downloadURL, _ := item["url"].(string)
+	hash, _ := item["sha256"].(string)
+
+	downloadFile(run, id, name, downloadURL, hash)
+}
+
+// downloadReferencedFiles is the OnItem hook for content endpoints (blog
+// posts, pages, marketing emails): it walks the item looking for
+// Hubspot-hosted asset URLs and downloads anything it finds, so a restore
+// has the images/PDFs/videos the content actually depends on, not just the
+// files the File Manager listing happens to enumerate.
+func downloadReferencedFiles(run *backupRun, item map[string]interface{}) {
+	found := map[string]bool{}
+	collectFileReferences(item, found)
+
+	for fileURL := range found {
+		fileURL := fileURL
+		name := fileURL
+		if idx := strings.LastIndex(fileURL, "/"); idx >= 0 && idx+1 < len(fileURL) {
+			name = fileURL[idx+1:]
+		}
+		run.submit(func() { downloadFile(run, referencedFileID(fileURL), name, fileURL, "") })
+	}
+}
+
+// collectFileReferences recursively walks decoded JSON looking for string
+// values that point at Hubspot-hosted assets.
+func collectFileReferences(v interface{}, out map[string]bool) {
+	switch val := v.(type) {
+	case string:
+		if isHubspotFileURL(val) {
+			out[val] = true
+		}
+	case map[string]interface{}:
+		for _, nested := range val {
+			collectFileReferences(nested, out)
+		}
+	case []interface{}:
+		for _, nested := range val {
+			collectFileReferences(nested, out)
+		}
+	}
+}
+
+func isHubspotFileURL(s string) bool {
+	lower := strings.ToLower(s)
+	if !strings.HasPrefix(lower, "http") {
+		return false
+	}
+	for _, marker := range cdnHostMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// referencedFileID derives a stable ID for a file we only know by URL (no
+// File Manager ID available from a content reference).
+func referencedFileID(fileURL string) string {
+	sum := sha256.Sum256([]byte(fileURL))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// downloadFile fetches downloadURL once per run (per known hash or, failing
+// that, per URL) and writes it both to the content-addressed blob store (so
+// a later run with the same file is a no-op) and to the friendly
+// hubspot-backup/<date>/files/<id>/<name> path restores actually want.
+//
+// Large files (PDFs, videos) are streamed to a local temp file while being
+// hashed, rather than buffered in memory, and that temp file is then
+// streamed on to the sink so a run never has to hold a whole file's bytes
+// in RAM.
+func downloadFile(run *backupRun, id, name, downloadURL, knownHash string) {
+	if downloadURL == "" {
+		return
+	}
+
+	dedupeKey := knownHash
+	if dedupeKey == "" {
+		dedupeKey = downloadURL
+	}
+	if _, seen := run.seenFiles.LoadOrStore(dedupeKey, true); seen {
+		return
+	}
+	if knownHash != "" {
+		if exists, err := run.sink.Exists(blobPath(knownHash)); err == nil && exists {
+			return
+		}
+	}
+
+	resp, err := http.Get(downloadURL)
+	if err != nil {
+		recordError("files", "download_error")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode > 299 {
+		recordError("files", fmt.Sprintf("download_http_%d", resp.StatusCode))
+		return
+	}
+
+	tmp, err := ioutil.TempFile("", "hubspot-backup-file-*")
+	if err != nil {
+		recordError("files", "tempfile_error")
+		return
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), resp.Body)
+	tmp.Close()
+	if err != nil {
+		recordError("files", "download_read_error")
+		return
+	}
+
+	hash := knownHash
+	if hash == "" {
+		hash = hex.EncodeToString(hasher.Sum(nil))
+		if _, seen := run.seenFiles.LoadOrStore(hash, true); seen {
+			return
+		}
+	}
+
+	if err := streamBlob(run.sink, hash, tmpPath, size); err != nil {
+		recordError("files", "blob_write_error")
+		return
+	}
+	if err := streamFromFile(run.sink, filePath(run.date, id, name), tmpPath, size); err != nil {
+		recordError("files", "write_error")
+		return
+	}
+
+	recordItems("files", 1)
+	run.progress.Update("files", 0, 1)
+}
+
+// streamBlob is writeBlob's counterpart for content that's already on disk,
+// so downloadFile never has to hold a whole file's bytes in memory to
+// content-address it.
+func streamBlob(sink Sink, hash, tmpPath string, size int64) error {
+	path := blobPath(hash)
+	exists, err := sink.Exists(path)
+	if err != nil {
+		return err
+	}
+	if exists {
+		// already have this exact content, nothing to do
+		return nil
+	}
+	return streamFromFile(sink, path, tmpPath, size)
+}
+
+// streamFromFile opens tmpPath fresh and streams it to path via the sink's
+// PutStream, so the same local temp file can be written out to more than
+// one destination without re-reading it into memory.
+func streamFromFile(sink Sink, path, tmpPath string, size int64) error {
+	f, err := os.Open(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return sink.PutStream(context.Background(), path, f, size)
+}
+
+func filePath(date, id, name string) string {
+	return "hubspot-backup/" + date + "/files/" + id + "/" + name
+}
@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -18,10 +19,41 @@ import (
 	"github.com/fatih/color"
 )
 
+var flagConcurrency = flag.Int("concurrency", 5, "number of concurrent Hubspot API workers")
+
 func main() {
+	// --diff is handled before flag.Parse(), since flag would otherwise
+	// choke on "--diff" itself as an unregistered flag: pull the two dates
+	// off os.Args directly and parse everything after them (e.g. --sink=s3
+	// and its --s3-* friends) as normal flags.
+	if len(os.Args) >= 4 && os.Args[1] == "--diff" {
+		date1, date2 := os.Args[2], os.Args[3]
+		if err := flag.CommandLine.Parse(os.Args[4:]); err != nil {
+			os.Exit(2)
+		}
+		sink, err := buildSink()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := runDiff(sink, date1, date2); err != nil {
+			fmt.Fprintf(os.Stderr, "diff failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	hapikey := getHapikey()
 
-	startBackup(hapikey)
+	sink, err := buildSink()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	startMetricsServer(*flagMetricsAddr)
+
+	startBackup(hapikey, sink)
 
 	switch runtime.GOOS {
 	case "windows":
@@ -43,6 +75,16 @@ type HubspotAccount struct {
 type HubspotConfig struct {
 	Hapikey    string `json: hapiKey`
 	PrivateApp bool   `json: privateApp`
+
+	// OAuth app credentials, used instead of Hapikey when set. See oauth.go.
+	OAuth        bool
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+
+	accessToken string
+	expiresAt   time.Time
+	mu          sync.Mutex
 }
 
 type Error struct {
@@ -56,7 +98,36 @@ func getHapikey() *HubspotConfig {
 	// command line flags
 	flag_hapikey := flag.String("hapikey", "", "Hubspot API key")
 	flag_accesskey := flag.String("accesskey", "", "Hubspot API access key")
+	flag_clientID := flag.String("client-id", "", "Hubspot OAuth app client ID")
+	flag_clientSecret := flag.String("client-secret", "", "Hubspot OAuth app client secret")
+	flag_refreshToken := flag.String("refresh-token", "", "Hubspot OAuth refresh token")
 	flag.Parse()
+
+	clientID := *flag_clientID
+	if clientID == "" {
+		clientID = os.Getenv("HUBSPOT_CLIENT_ID")
+	}
+	clientSecret := *flag_clientSecret
+	if clientSecret == "" {
+		clientSecret = os.Getenv("HUBSPOT_CLIENT_SECRET")
+	}
+	refreshToken := *flag_refreshToken
+	if refreshToken == "" {
+		refreshToken = os.Getenv("HUBSPOT_REFRESH_TOKEN")
+	}
+
+	// OAuth apps take priority over hapikey/accesskey when all three OAuth
+	// values are present; the access token itself is fetched lazily on
+	// first use (see HubspotConfig.token in oauth.go).
+	if clientID != "" && clientSecret != "" && refreshToken != "" {
+		return &HubspotConfig{
+			OAuth:        true,
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RefreshToken: refreshToken,
+		}
+	}
+
 	// if hapikey in arguments, use it, else use env variable
 	if *flag_hapikey != "" {
 		hapikey = *flag_hapikey
@@ -144,481 +215,193 @@ func answerQuestion(question string) string {
 	return strings.Trim(text, " \n")
 }
 
-func executeRequest(hubspotConfig *HubspotConfig, url string) (*http.Response, error) {
+// maxRequestAttempts bounds how many times executeRequest retries a single
+// request before giving up and surfacing a hard error to the caller.
+const maxRequestAttempts = 5
+
+func executeRequest(hubspotConfig *HubspotConfig, limiter *RateLimiter, endpoint string, url string) (*http.Response, error) {
 	// Create a new GET request
-	if !hubspotConfig.PrivateApp {
+	if !hubspotConfig.PrivateApp && !hubspotConfig.OAuth {
 		url += "&hapikey=" + hubspotConfig.Hapikey
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
-		return nil, err
-	}
-
-	if hubspotConfig.PrivateApp {
-		req.Header.Set("Authorization", "Bearer "+hubspotConfig.Hapikey)
-	}
-
-	// Create an HTTP client
 	client := &http.Client{}
 
-	// Send the request using the client
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error making request: %v\n", err)
-		return resp, err
-	}
-
-	return resp, nil
-}
-
-func startBackup(hapikey *HubspotConfig) {
-	var wg sync.WaitGroup
-
-	switch runtime.GOOS {
-	case "windows":
-		color.Yellow("\033[32;1mBacking up your Hubspot account...\033[0m \n")
-	default:
-		fmt.Printf("\033[32;1mBacking up your Hubspot account...\033[0m \n")
-	}
-
-	// https://www.sohamkamani.com/blog/2017/10/18/parsing-json-in-golang/#unstructured-data-decoding-json-to-maps
-	// https://astaxie.gitbooks.io/build-web-application-with-golang/en/07.2.html
-	wg.Add(16)
-	go backupHasMore(hapikey, "https://api.hubapi.com/contacts/v1/lists", "lists", 0, &wg)
-	go backupOnce(hapikey, "https://api.hubapi.com/content/api/v2/blogs", "blogs", 0, &wg)
-	go backupLimit(hapikey, "https://api.hubapi.com/content/api/v2/blog-posts", "blog-posts", 0, &wg)
-	go backupLimit(hapikey, "https://api.hubapi.com/blogs/v3/blog-authors", "blog-authors", 0, &wg)
-	go backupLimit(hapikey, "https://api.hubapi.com/blogs/v3/topics", "blog-topics", 0, &wg)
-	go backupLimit(hapikey, "https://api.hubapi.com/comments/v3/comments", "blog-comments", 0, &wg)
-	go backupLimit(hapikey, "https://api.hubapi.com/content/api/v2/layouts", "layouts", 0, &wg)
-	go backupLimit(hapikey, "https://api.hubapi.com/content/api/v2/pages", "pages", 0, &wg)
-	go backupOnce(hapikey, "https://api.hubapi.com/hubdb/api/v2/tables", "hubdb-tables", 0, &wg)
-	go backupLimit(hapikey, "https://api.hubapi.com/content/api/v2/templates", "templates", 0, &wg)
-	go backupLimit(hapikey, "https://api.hubapi.com/url-mappings/v3/url-mappings", "url-mappings", 0, &wg)
-	go backupHasMore(hapikey, "https://api.hubapi.com/deals/v1/deal/paged", "deals", 0, &wg)
-	go backupLimit(hapikey, "https://api.hubapi.com/marketing-emails/v1/emails", "marketing-emails", 0, &wg)
-	go backupOnce(hapikey, "https://api.hubapi.com/automation/v3/workflows", "workflows", 0, &wg)
-	go backupHasMore(hapikey, "https://api.hubapi.com/companies/v2/companies/paged", "companies", 0, &wg)
-	go backupContacts(hapikey, "https://api.hubapi.com/contacts/v1/lists/all/contacts/all", "contacts", 0, &wg)
-	//go backupLimit(hapikey, "https://api.hubapi.com/forms/v2/forms", "forms", 0, &wg) // TODO: typeArray in results, without nesting
-
-	wg.Wait()
-	ex, err := os.Executable()
-	if err != nil {
-		panic(err)
-	}
-	exPath := filepath.Dir(ex)
-
-	switch runtime.GOOS {
-	case "windows":
-		color.Green("\033[32;1m############\nBackup saved in %v/hubspot-backup/%v\033[0m \n", exPath, time.Now().Format("2006-01-02"))
-	default:
-		fmt.Printf("\033[32;1m############\nBackup saved in %v/hubspot-backup/%v\033[0m \n", exPath, time.Now().Format("2006-01-02"))
-	}
-	return
-}
-
-func backupHasMore(hubspotConfig *HubspotConfig, url string, endpoint string, offset float64, wg *sync.WaitGroup) {
-	defer wg.Done()
-	var error Error
-	var results map[string]interface{}
-
-	// get data from API
-	resp, err := executeRequest(hubspotConfig, strings.TrimSpace(url+"?count=250&offset="+strconv.Itoa(int(offset))))
-	if err != nil {
-		fmt.Println(err)
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body) // body as bytes
-
-	if resp.StatusCode > 299 {
-		// if error
-		fmt.Printf("\033[31;1mError: %v %v \033[0m\n", resp.StatusCode, http.StatusText(resp.StatusCode))
-		err = json.Unmarshal(body, &error)
-		fmt.Println(error.Message)
-
-		return
-	} else {
-		// continue
-		err = json.Unmarshal(body, &results) // put json body response into map of strings to empty interfaces
+	var lastErr error
+	for attempt := 1; attempt <= maxRequestAttempts; attempt++ {
+		limiter.Wait()
 
+		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
-			panic(err)
+			fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
+			return nil, err
 		}
 
-		// create folder
-		folderpath := "hubspot-backup/" + time.Now().Format("2006-01-02") + "/" + endpoint
-		os.MkdirAll(folderpath, 0700)
-
-		// get items from response
-		var typeArray []interface{}
-
-		// sometimes results are within "objects" field and sometimes within endpoint name
-		if results["objects"] != nil {
-			typeArray = results["objects"].([]interface{})
-		} else if results[endpoint] != nil {
-			typeArray = results[endpoint].([]interface{})
-		}
-		if len(typeArray) == 0 {
-			// finish if went through all records
-			switch runtime.GOOS {
-			case "windows":
-				color.Green("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			default:
-				fmt.Printf("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			}
-			return
-		}
-
-		switch runtime.GOOS {
-		case "windows":
-			color.Yellow("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, len(typeArray)+int(offset))
-		default:
-			fmt.Printf("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, len(typeArray)+int(offset))
-		}
-
-		// for each item
-		for k, v := range typeArray {
-			itemnumber := k + int(offset)
-			filepath := string(folderpath + "/" + strconv.Itoa(itemnumber) + ".json")
-			// create file
-			file, err := os.Create(filepath)
-			if err != nil {
-				fmt.Println("failed creating file: %s", err)
-			}
-			// create json
-			json, err := json.Marshal(v)
-			if err != nil {
-				fmt.Println(err)
-			}
-			// write json to file
-			file.WriteString(string(json[:]))
-
+		switch {
+		case hubspotConfig.OAuth:
+			token, err := hubspotConfig.token()
 			if err != nil {
-				fmt.Println("failed writing to file: %s", err)
+				lastErr = err
+				recordError(endpoint, "oauth_token_error")
+				time.Sleep(backoffDelay(attempt))
+				continue
 			}
-			file.Close()
+			req.Header.Set("Authorization", "Bearer "+token)
+		case hubspotConfig.PrivateApp:
+			req.Header.Set("Authorization", "Bearer "+hubspotConfig.Hapikey)
 		}
 
-		// rerun function if there are more results
-		has_more := results["has-more"]
-		if has_more != false {
-			new_offset := results["offset"]
-			wg.Add(1)
-			go backupHasMore(hubspotConfig, url, endpoint, new_offset.(float64), wg)
-		} else {
-			switch runtime.GOOS {
-			case "windows":
-				color.Green("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			default:
-				fmt.Printf("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			}
-		}
-	}
-	return
-}
-
-func backupOnce(hubspotConfig *HubspotConfig, url string, endpoint string, offset float64, wg *sync.WaitGroup) {
-	defer wg.Done()
-	var error Error
-	var results map[string]interface{}
-
-	// get data from API
-	resp, err := executeRequest(hubspotConfig, strings.TrimSpace(url+"?count=250&offset="+strconv.Itoa(int(offset))))
-	if err != nil {
-		fmt.Println(err)
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body) // body as bytes
-
-	if resp.StatusCode > 299 {
-		// if error
-		fmt.Printf("\033[31;1mError: %v %v \033[0m\n", resp.StatusCode, http.StatusText(resp.StatusCode))
-		err = json.Unmarshal(body, &error)
-		fmt.Println(error.Message)
-
-		return
-	} else {
-		// continue
-		err = json.Unmarshal(body, &results) // put json body response into map of strings to empty interfaces
-
+		start := time.Now()
+		resp, err := client.Do(req)
 		if err != nil {
-			panic(err)
-		}
-		switch runtime.GOOS {
-		case "windows":
-			color.Yellow("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, int(offset))
-		default:
-			fmt.Printf("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, int(offset))
+			lastErr = err
+			recordError(endpoint, "transport_error")
+			time.Sleep(backoffDelay(attempt))
+			continue
 		}
 
-		// create folder
-		folderpath := "hubspot-backup/" + time.Now().Format("2006-01-02") + "/" + endpoint
-		os.MkdirAll(folderpath, 0700)
-
-		// get items from response
-		var typeArray []interface{}
-
-		// sometimes results are within "objects" field and sometimes within endpoint name
-		if results["objects"] != nil {
-			typeArray = results["objects"].([]interface{})
-		} else if results[endpoint] != nil {
-			typeArray = results[endpoint].([]interface{})
-		}
-		if len(typeArray) == 0 {
-			// finish if went through all records
-			switch runtime.GOOS {
-			case "windows":
-				color.Green("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			default:
-				fmt.Printf("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
+		if resp.StatusCode == http.StatusUnauthorized && hubspotConfig.OAuth {
+			lastErr = fmt.Errorf("hubspot returned 401 Unauthorized")
+			recordHTTPResult(endpoint, resp.StatusCode, time.Since(start))
+			resp.Body.Close()
+			if _, err := hubspotConfig.refreshToken(); err != nil {
+				lastErr = err
 			}
-			return
-		}
-		switch runtime.GOOS {
-		case "windows":
-			color.Yellow("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, len(typeArray)+int(offset))
-		default:
-			fmt.Printf("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, len(typeArray)+int(offset))
+			continue
 		}
 
-		// for each item
-		for k, v := range typeArray {
-			itemnumber := k + int(offset)
-			filepath := string(folderpath + "/" + strconv.Itoa(itemnumber) + ".json")
-			// create file
-			file, err := os.Create(filepath)
-			if err != nil {
-				fmt.Println("failed creating file: %s", err)
-			}
-			// create json
-			json, err := json.Marshal(v)
-			if err != nil {
-				fmt.Println(err)
-			}
-			// write json to file
-			file.WriteString(string(json[:]))
-
-			if err != nil {
-				fmt.Println("failed writing to file: %s", err)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("hubspot returned %v %v", resp.StatusCode, http.StatusText(resp.StatusCode))
+			recordHTTPResult(endpoint, resp.StatusCode, time.Since(start))
+			wait := retryAfter(resp)
+			resp.Body.Close()
+			if wait <= 0 {
+				wait = backoffDelay(attempt)
 			}
-			file.Close()
+			time.Sleep(wait)
+			continue
 		}
 
-		switch runtime.GOOS {
-		case "windows":
-			color.Green("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-		default:
-			fmt.Printf("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-		}
+		recordHTTPResult(endpoint, resp.StatusCode, time.Since(start))
+		return resp, nil
 	}
-	return
-}
 
-func backupLimit(hubspotConfig *HubspotConfig, url string, endpoint string, offset float64, wg *sync.WaitGroup) {
-	defer wg.Done()
-	var error Error
-	var results map[string]interface{}
+	fmt.Fprintf(os.Stderr, "Error making request: %v\n", lastErr)
+	recordError(endpoint, "retries_exhausted")
+	return nil, fmt.Errorf("giving up after %d attempts: %w", maxRequestAttempts, lastErr)
+}
 
-	// get data from API
-	resp, err := executeRequest(hubspotConfig, strings.TrimSpace(url+"?count=250&offset="+strconv.Itoa(int(offset))))
-	if err != nil {
-		fmt.Println(err)
+// retryAfter reads the Retry-After header, which Hubspot sends as either a
+// number of seconds or an HTTP-date, returning zero if absent or unparsable.
+func retryAfter(resp *http.Response) time.Duration {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body) // body as bytes
-
-	if resp.StatusCode > 299 {
-		// if error
-		fmt.Printf("\033[31;1mError: %v %v \033[0m\n", resp.StatusCode, http.StatusText(resp.StatusCode))
-		err = json.Unmarshal(body, &error)
-		fmt.Println(error.Message)
-
-		return
-	} else {
-		// continue
-		err = json.Unmarshal(body, &results) // put json body response into map of strings to empty interfaces
-
-		if err != nil {
-			panic(err)
-		}
-
-		switch runtime.GOOS {
-		case "windows":
-			color.Yellow("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, int(offset))
-		default:
-			fmt.Printf("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, int(offset))
-		}
-
-		// create folder
-		folderpath := "hubspot-backup/" + time.Now().Format("2006-01-02") + "/" + endpoint
-		os.MkdirAll(folderpath, 0700)
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
 
-		// get items from response
-		var typeArray []interface{}
+// backoffDelay returns an exponentially increasing delay with jitter for
+// the given attempt number (1-indexed).
+func backoffDelay(attempt int) time.Duration {
+	base := time.Duration(1<<uint(attempt-1)) * 250 * time.Millisecond
+	jitter := time.Duration(rand.Int63n(int64(base/2) + 1))
+	return base + jitter
+}
 
-		// sometimes results are within "objects" field and sometimes within endpoint name
-		if results["objects"] != nil {
-			typeArray = results["objects"].([]interface{})
-		} else if results[endpoint] != nil {
-			typeArray = results[endpoint].([]interface{})
-		}
-		if len(typeArray) == 0 {
-			// finish if went through all records
-			switch runtime.GOOS {
-			case "windows":
-				color.Green("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			default:
-				fmt.Printf("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			}
-			return
-		}
+// backupRun bundles everything a backup worker needs that would otherwise
+// have to be threaded through as an ever-growing argument list: the
+// Hubspot config, where output lands, the run's dedup manifest, and the
+// bounded pool/rate limiter every worker shares.
+type backupRun struct {
+	hubspotConfig *HubspotConfig
+	sink          Sink
+	manifest      *Manifest
+	mu            *sync.Mutex
+	wg            *sync.WaitGroup
+	pool          *Pool
+	limiter       *RateLimiter
+	progress      Progress
+	starts        *sync.Map
+
+	date      string
+	seenFiles *sync.Map
+}
 
-		// for each item
-		for k, v := range typeArray {
-			itemnumber := k + int(offset)
-			filepath := string(folderpath + "/" + strconv.Itoa(itemnumber) + ".json")
-			// create file
-			file, err := os.Create(filepath)
-			if err != nil {
-				fmt.Println("failed creating file: %s", err)
-			}
-			// create json
-			json, err := json.Marshal(v)
-			if err != nil {
-				fmt.Println(err)
-			}
-			// write json to file
-			file.WriteString(string(json[:]))
+func (r *backupRun) submit(job func()) {
+	r.wg.Add(1)
+	r.pool.Submit(func() {
+		defer r.wg.Done()
+		job()
+	})
+}
 
-			if err != nil {
-				fmt.Println("failed writing to file: %s", err)
-			}
-			file.Close()
-		}
+// startEndpoint records when an endpoint's first page was fetched, so
+// finishEndpoint can report how long the whole endpoint took across every
+// page. It's a no-op for pages after the first.
+func (r *backupRun) startEndpoint(endpoint string) {
+	r.starts.LoadOrStore(endpoint, time.Now())
+}
 
-		if len(typeArray) == 0 {
-			// finish if went through all records
-			switch runtime.GOOS {
-			case "windows":
-				color.Green("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			default:
-				fmt.Printf("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			}
-			return
-		} else {
-			switch runtime.GOOS {
-			case "windows":
-				color.Yellow("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, len(typeArray)+int(offset))
-			default:
-				fmt.Printf("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, len(typeArray)+int(offset))
-			}
-			// run again to save next batch
-			wg.Add(1)
-			go backupLimit(hubspotConfig, url, endpoint, float64(len(typeArray))+offset, wg)
-		}
+// finishEndpoint reports an endpoint as done to both Prometheus and the
+// active Progress renderer.
+func (r *backupRun) finishEndpoint(endpoint string, status string) {
+	var duration time.Duration
+	if start, ok := r.starts.Load(endpoint); ok {
+		duration = time.Since(start.(time.Time))
 	}
-	return
+	recordEndpointDuration(endpoint, duration)
+	r.progress.Done(endpoint, status, duration)
 }
 
-func backupContacts(hubspotConfig *HubspotConfig, url string, endpoint string, offset float64, wg *sync.WaitGroup) {
-	defer wg.Done()
-	var error Error
-	var results map[string]interface{}
-
-	// get data from API
-	resp, err := executeRequest(hubspotConfig, strings.TrimSpace(url+"?count=100&vidOffset="+strconv.Itoa(int(offset))))
-	if err != nil {
-		fmt.Println(err)
+func startBackup(hapikey *HubspotConfig, sink Sink) {
+	date := time.Now().Format("2006-01-02")
+	manifest := newManifest(date, findPreviousRun(sink, date))
+
+	run := &backupRun{
+		hubspotConfig: hapikey,
+		sink:          sink,
+		manifest:      manifest,
+		mu:            &sync.Mutex{},
+		wg:            &sync.WaitGroup{},
+		pool:          NewPool(*flagConcurrency),
+		limiter:       NewRateLimiter(defaultRateLimit),
+		progress:      newProgress(),
+		starts:        &sync.Map{},
+		date:          date,
+		seenFiles:     &sync.Map{},
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body) // body as bytes
-
-	if resp.StatusCode > 299 {
-		// if error
-		fmt.Printf("\033[31;1mError: %v %v \033[0m\n", resp.StatusCode, http.StatusText(resp.StatusCode))
-		err = json.Unmarshal(body, &error)
-		fmt.Println(error.Message)
-
-		return
-	} else {
-		// continue
-		err = json.Unmarshal(body, &results) // put json body response into map of strings to empty interfaces
-
-		if err != nil {
-			panic(err)
-		}
 
-		// create folder
-		folderpath := "hubspot-backup/" + time.Now().Format("2006-01-02") + "/" + endpoint
-		os.MkdirAll(folderpath, 0700)
+	run.progress.RunStarted()
 
-		// get items from response
-		var typeArray []interface{}
+	endpoints := append([]Endpoint{}, staticEndpoints...)
+	endpoints = append(endpoints, discoverCustomObjectEndpoints(run)...)
 
-		// sometimes results are within "objects" field and sometimes within endpoint name
-		if results["objects"] != nil {
-			typeArray = results["objects"].([]interface{})
-		} else if results[endpoint] != nil {
-			typeArray = results[endpoint].([]interface{})
-		}
-		if len(typeArray) == 0 {
-			// finish if went through all records
-			switch runtime.GOOS {
-			case "windows":
-				color.Green("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			default:
-				fmt.Printf("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			}
-			return
-		}
+	for _, ep := range endpoints {
+		ep := ep
+		run.submit(func() { backupEndpoint(run, ep, ep.Paginator.InitialOffset()) })
+	}
 
-		switch runtime.GOOS {
-		case "windows":
-			color.Yellow("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, len(typeArray)+int(offset))
-		default:
-			fmt.Printf("\r\033[33;1mBacking up %v: %v\033[0m", endpoint, len(typeArray)+int(offset))
-		}
+	run.wg.Wait()
+	run.pool.Close()
 
-		// for each item
-		for k, v := range typeArray {
-			itemnumber := k + int(offset)
-			filepath := string(folderpath + "/" + strconv.Itoa(itemnumber) + ".json")
-			// create file
-			file, err := os.Create(filepath)
-			if err != nil {
-				fmt.Println("failed creating file: %s", err)
-			}
-			// create json
-			json, err := json.Marshal(v)
-			if err != nil {
-				fmt.Println(err)
-			}
-			// write json to file
-			file.WriteString(string(json[:]))
-
-			if err != nil {
-				fmt.Println("failed writing to file: %s", err)
-			}
-			file.Close()
-		}
+	if err := saveManifest(sink, manifest); err != nil {
+		fmt.Printf("\033[31;1mError: failed to save manifest: %v \033[0m\n", err)
+	}
 
-		// rerun function if there are more results
-		has_more := results["has-more"]
-		if has_more != false {
-			new_offset := results["vid-offset"]
-			time.Sleep(1 * time.Second)
-			go backupContacts(hubspotConfig, url, endpoint, new_offset.(float64), wg)
-		} else {
-			switch runtime.GOOS {
-			case "windows":
-				color.Green("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			default:
-				fmt.Printf("\n\033[32;1mBacked up all %v \033[0m\n", endpoint)
-			}
-		}
+	ex, err := os.Executable()
+	if err != nil {
+		panic(err)
 	}
+	exPath := filepath.Dir(ex)
+
+	run.progress.RunFinished(fmt.Sprintf("%v/hubspot-backup/%v", exPath, date))
 	return
 }
+
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Manifest records, for a single backup run, which blob hash each
+// endpoint/item id resolved to, plus the date of the run it was based on.
+// This lets us skip rewriting unchanged items while still being able to
+// reconstruct the full set of objects that made up any given day.
+type Manifest struct {
+	Date      string                       `json:"date"`
+	Parent    string                       `json:"parent,omitempty"`
+	Endpoints map[string]map[string]string `json:"endpoints"` // endpoint -> item id -> blob hash
+}
+
+func newManifest(date, parent string) *Manifest {
+	return &Manifest{
+		Date:      date,
+		Parent:    parent,
+		Endpoints: make(map[string]map[string]string),
+	}
+}
+
+func manifestPath(date string) string {
+	return "hubspot-backup/" + date + "/manifest.json"
+}
+
+const objectsPrefix = "hubspot-backup/objects"
+
+func blobPath(hash string) string {
+	return objectsPrefix + "/" + hash[:2] + "/" + hash + ".json"
+}
+
+// writeBlob stores data in the content-addressed object store, skipping the
+// write entirely if a blob with the same hash already exists.
+func writeBlob(sink Sink, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	path := blobPath(hash)
+
+	exists, err := sink.Exists(path)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		// already have this exact content, nothing to do
+		return hash, nil
+	}
+
+	if err := sink.Put(context.Background(), path, data); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// recordItem hashes data, writes it to the object store if new, and records
+// the resulting hash for endpoint/itemID in the manifest.
+func recordItem(sink Sink, manifest *Manifest, mu *sync.Mutex, endpoint, itemID string, data []byte) error {
+	hash, err := writeBlob(sink, data)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	if manifest.Endpoints[endpoint] == nil {
+		manifest.Endpoints[endpoint] = make(map[string]string)
+	}
+	manifest.Endpoints[endpoint][itemID] = hash
+	mu.Unlock()
+
+	return nil
+}
+
+// loadManifest reads a run's manifest back through sink, so --diff works
+// against whichever sink the runs being compared were actually written to
+// (local disk, S3, ...), not just ones that happen to exist on local disk.
+func loadManifest(sink Sink, date string) (*Manifest, error) {
+	data, err := sink.Get(manifestPath(date))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func saveManifest(sink Sink, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return sink.Put(context.Background(), manifestPath(m.Date), data)
+}
+
+// findPreviousRun returns the most recent date (other than date itself) under
+// hubspot-backup/ that has its own manifest.json, so a new run can chain off
+// of it as parent.
+func findPreviousRun(sink Sink, date string) string {
+	paths, err := sink.List("hubspot-backup/")
+	if err != nil {
+		return ""
+	}
+
+	var dates []string
+	for _, p := range paths {
+		if !strings.HasSuffix(p, "/manifest.json") {
+			continue
+		}
+		rest := strings.TrimPrefix(p, "hubspot-backup/")
+		runDate := strings.TrimSuffix(rest, "/manifest.json")
+		if runDate == "" || runDate == date {
+			continue
+		}
+		dates = append(dates, runDate)
+	}
+	if len(dates) == 0 {
+		return ""
+	}
+	sort.Strings(dates)
+	return dates[len(dates)-1]
+}
+
+// runDiff walks the manifests for date1 and date2 and prints, per endpoint,
+// which item ids were added, removed, or modified (hash changed) between
+// the two runs.
+func runDiff(sink Sink, date1, date2 string) error {
+	m1, err := loadManifest(sink, date1)
+	if err != nil {
+		return fmt.Errorf("loading manifest for %v: %w", date1, err)
+	}
+	m2, err := loadManifest(sink, date2)
+	if err != nil {
+		return fmt.Errorf("loading manifest for %v: %w", date2, err)
+	}
+
+	endpoints := make(map[string]bool)
+	for e := range m1.Endpoints {
+		endpoints[e] = true
+	}
+	for e := range m2.Endpoints {
+		endpoints[e] = true
+	}
+
+	var names []string
+	for e := range endpoints {
+		names = append(names, e)
+	}
+	sort.Strings(names)
+
+	for _, endpoint := range names {
+		before := m1.Endpoints[endpoint]
+		after := m2.Endpoints[endpoint]
+
+		var added, removed, modified []string
+		for id, hash := range after {
+			oldHash, ok := before[id]
+			if !ok {
+				added = append(added, id)
+			} else if oldHash != hash {
+				modified = append(modified, id)
+			}
+		}
+		for id := range before {
+			if _, ok := after[id]; !ok {
+				removed = append(removed, id)
+			}
+		}
+
+		if len(added) == 0 && len(removed) == 0 && len(modified) == 0 {
+			continue
+		}
+
+		sort.Strings(added)
+		sort.Strings(removed)
+		sort.Strings(modified)
+
+		fmt.Printf("%v:\n", endpoint)
+		for _, id := range added {
+			fmt.Printf("  + %v\n", id)
+		}
+		for _, id := range removed {
+			fmt.Printf("  - %v\n", id)
+		}
+		for _, id := range modified {
+			fmt.Printf("  ~ %v\n", id)
+		}
+	}
+
+	return nil
+}
+
+// storeItems hashes and records each item of a fetched page into the
+// content-addressed object store, skipping items whose content already
+// exists under the same hash. offset is the page's raw Paginator
+// offset/cursor (not its display form), so itemID's fallback stays unique
+// across pages even for cursor-paginated endpoints; see itemID.
+func storeItems(sink Sink, manifest *Manifest, mu *sync.Mutex, endpoint string, offset string, typeArray []interface{}) {
+	for k, v := range typeArray {
+		data, err := json.Marshal(v)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		id := itemID(v, offset+":"+strconv.Itoa(k))
+		if err := recordItem(sink, manifest, mu, endpoint, id, data); err != nil {
+			fmt.Printf("failed storing %v item %v: %v\n", endpoint, id, err)
+		}
+	}
+}
+
+// itemID extracts a stable identifier for a backed-up record, falling back
+// to fallback when the payload has none of the id fields Hubspot typically
+// uses. fallback must already be unique per item within a run: it's the
+// page's raw offset/cursor plus the item's position within that page, so
+// two items lacking an id on different pages don't collide even for
+// cursor-paginated endpoints, where offsetForDisplay's int form is always 0.
+func itemID(v interface{}, fallback string) string {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return fallback
+	}
+
+	for _, key := range []string{"id", "vid", "guid", "objectId"} {
+		if raw, ok := obj[key]; ok {
+			switch val := raw.(type) {
+			case string:
+				return val
+			case float64:
+				return strconv.FormatFloat(val, 'f', -1, 64)
+			}
+		}
+	}
+
+	return fallback
+}
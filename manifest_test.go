@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+// memSink is a minimal in-memory Sink, standing in for LocalSink/S3Sink in
+// tests that only care about the read/write/exists contract, not where
+// bytes actually land.
+type memSink struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemSink() *memSink {
+	return &memSink{data: make(map[string][]byte)}
+}
+
+func (s *memSink) Put(ctx context.Context, path string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[path] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *memSink) PutStream(ctx context.Context, path string, r io.Reader, size int64) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, path, data)
+}
+
+func (s *memSink) Get(path string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.data[path]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func (s *memSink) Exists(path string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.data[path]
+	return ok, nil
+}
+
+func (s *memSink) List(prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for path := range s.data {
+		names = append(names, path)
+	}
+	return names, nil
+}
+
+func TestWriteBlobDedupesByHash(t *testing.T) {
+	sink := newMemSink()
+
+	hash1, err := writeBlob(sink, []byte(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("writeBlob: %v", err)
+	}
+	hash2, err := writeBlob(sink, []byte(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("writeBlob: %v", err)
+	}
+	if hash1 != hash2 {
+		t.Errorf("identical content hashed to %q then %q, want the same hash", hash1, hash2)
+	}
+	if len(sink.data) != 1 {
+		t.Errorf("writing identical content twice stored %d blobs, want 1", len(sink.data))
+	}
+
+	if _, err := writeBlob(sink, []byte(`{"a":2}`)); err != nil {
+		t.Fatalf("writeBlob: %v", err)
+	}
+	if len(sink.data) != 2 {
+		t.Errorf("writing distinct content stored %d blobs, want 2", len(sink.data))
+	}
+}
+
+func TestItemIDPrefersKnownFields(t *testing.T) {
+	cases := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"id field, string", map[string]interface{}{"id": "abc"}, "abc"},
+		{"id field, float64", map[string]interface{}{"id": float64(42)}, "42"},
+		{"vid field", map[string]interface{}{"vid": float64(7)}, "7"},
+		{"guid field", map[string]interface{}{"guid": "g-1"}, "g-1"},
+		{"objectId field", map[string]interface{}{"objectId": "o-1"}, "o-1"},
+		{"no known field", map[string]interface{}{"name": "no id here"}, "fallback"},
+		{"not an object", "just a string", "fallback"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := itemID(c.v, "fallback"); got != c.want {
+				t.Errorf("itemID() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+// TestStoreItemsFallbackIsUniqueAcrossCursorPages is a regression test: for
+// cursor-paginated endpoints every page's offsetForDisplay is 0, so two
+// items missing an id field on different pages must not collide just
+// because they're both item k=0 in their page.
+func TestStoreItemsFallbackIsUniqueAcrossCursorPages(t *testing.T) {
+	sink := newMemSink()
+	manifest := newManifest("2024-01-01", "")
+	var mu sync.Mutex
+
+	page1 := []interface{}{map[string]interface{}{"name": "no id"}}
+	page2 := []interface{}{map[string]interface{}{"name": "also no id"}}
+
+	storeItems(sink, manifest, &mu, "tickets", "", page1)
+	storeItems(sink, manifest, &mu, "tickets", "cursor-abc", page2)
+
+	if len(manifest.Endpoints["tickets"]) != 2 {
+		t.Errorf("got %d manifest entries for two distinct items across pages, want 2: %v",
+			len(manifest.Endpoints["tickets"]), manifest.Endpoints["tickets"])
+	}
+}
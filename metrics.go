@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var flagMetricsAddr = flag.String("metrics-addr", "", "Address to serve Prometheus metrics on, e.g. :9090 (disabled if empty)")
+
+var (
+	itemsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hubspot_backup_items_total",
+		Help: "Number of Hubspot items backed up, by endpoint.",
+	}, []string{"endpoint"})
+
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hubspot_backup_http_requests_total",
+		Help: "Number of Hubspot API requests, by endpoint and response status.",
+	}, []string{"endpoint", "status"})
+
+	errorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hubspot_backup_errors_total",
+		Help: "Number of backup errors, by endpoint and reason.",
+	}, []string{"endpoint", "reason"})
+
+	requestLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "hubspot_backup_request_latency_seconds",
+		Help:    "Latency of individual Hubspot API requests.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	endpointDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "hubspot_backup_endpoint_duration_seconds",
+		Help:    "Total time spent backing up a single endpoint, across all of its pages.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"endpoint"})
+)
+
+// startMetricsServer serves the Prometheus metrics registered above on
+// --metrics-addr, if set. A scheduled/cron deployment of this tool can
+// scrape it for the duration of the run.
+func startMetricsServer(addr string) {
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Printf("metrics server stopped: %v\n", err)
+		}
+	}()
+}
+
+func recordHTTPResult(endpoint string, status int, duration time.Duration) {
+	httpRequestsTotal.WithLabelValues(endpoint, fmt.Sprintf("%d", status)).Inc()
+	requestLatency.Observe(duration.Seconds())
+}
+
+func recordError(endpoint, reason string) {
+	errorsTotal.WithLabelValues(endpoint, reason).Inc()
+}
+
+func recordItems(endpoint string, count int) {
+	itemsTotal.WithLabelValues(endpoint).Add(float64(count))
+}
+
+func recordEndpointDuration(endpoint string, duration time.Duration) {
+	endpointDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+}
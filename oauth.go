@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const oauthTokenURL = "https://api.hubapi.com/oauth/v1/token"
+
+// oauthTokenResponse is the subset of Hubspot's token exchange response we
+// care about.
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// token returns a valid OAuth access token, fetching or refreshing one
+// against oauthTokenURL if the cached token is missing or about to expire.
+// Safe to call concurrently from multiple workers.
+func (c *HubspotConfig) token() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.accessToken != "" && time.Now().Before(c.expiresAt) {
+		return c.accessToken, nil
+	}
+	return c.fetchTokenLocked()
+}
+
+// refreshToken forces a new access token to be fetched, even if the cached
+// one hasn't expired yet. Used when a request comes back 401 Unauthorized.
+func (c *HubspotConfig) refreshToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fetchTokenLocked()
+}
+
+// fetchTokenLocked exchanges RefreshToken for a new access token. c.mu must
+// already be held.
+func (c *HubspotConfig) fetchTokenLocked() (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("client_id", c.ClientID)
+	form.Set("client_secret", c.ClientSecret)
+	form.Set("refresh_token", c.RefreshToken)
+
+	resp, err := http.PostForm(oauthTokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("refreshing oauth token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading oauth token response: %w", err)
+	}
+
+	if resp.StatusCode > 299 {
+		return "", fmt.Errorf("oauth token exchange failed: %v %v", resp.StatusCode, string(body))
+	}
+
+	var parsed oauthTokenResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", fmt.Errorf("parsing oauth token response: %w", err)
+	}
+
+	// Refresh a little before the token actually expires, so a slow
+	// request started near expiry doesn't race the deadline.
+	c.accessToken = parsed.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(parsed.ExpiresIn)*time.Second - 60*time.Second)
+	return c.accessToken, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import "strconv"
+
+// Paginator knows how to walk a single Hubspot listing endpoint to
+// completion. Hubspot uses at least four incompatible pagination schemes
+// across its APIs, so each endpoint is registered with whichever
+// implementation matches how it paginates, instead of its own bespoke
+// backup function.
+type Paginator interface {
+	// InitialOffset is the offset/cursor value to fetch the first page with.
+	InitialOffset() string
+	// Query returns the pagination-related query string for the page
+	// fetched at offset (everything after the leading "?").
+	Query(offset string) string
+	// Next inspects the decoded response for a page that yielded itemCount
+	// items, returning the offset/cursor for the next page and whether
+	// there is one.
+	Next(offset string, results map[string]interface{}, itemCount int) (next string, more bool)
+}
+
+// hasMoreOffsetPaginator follows the v1 "has-more"/"offset" convention used
+// by lists, deals, and companies.
+type hasMoreOffsetPaginator struct{}
+
+func (hasMoreOffsetPaginator) InitialOffset() string { return "0" }
+
+func (hasMoreOffsetPaginator) Query(offset string) string {
+	return "count=250&offset=" + offset
+}
+
+func (hasMoreOffsetPaginator) Next(offset string, results map[string]interface{}, itemCount int) (string, bool) {
+	if itemCount == 0 || results["has-more"] == false || results["has-more"] == nil {
+		return "", false
+	}
+	return offsetValue(results["offset"]), true
+}
+
+// vidOffsetPaginator follows the contacts endpoint's "has-more"/"vid-offset"
+// convention, which is identical to hasMoreOffsetPaginator except for the
+// field names and its lower page size.
+type vidOffsetPaginator struct{}
+
+func (vidOffsetPaginator) InitialOffset() string { return "0" }
+
+func (vidOffsetPaginator) Query(offset string) string {
+	return "count=100&vidOffset=" + offset
+}
+
+func (vidOffsetPaginator) Next(offset string, results map[string]interface{}, itemCount int) (string, bool) {
+	if itemCount == 0 || results["has-more"] == false || results["has-more"] == nil {
+		return "", false
+	}
+	return offsetValue(results["vid-offset"]), true
+}
+
+// limitOffsetPaginator is for endpoints with no has-more flag at all: keep
+// paging by count+len(typeArray) until a page comes back empty.
+type limitOffsetPaginator struct{}
+
+func (limitOffsetPaginator) InitialOffset() string { return "0" }
+
+func (limitOffsetPaginator) Query(offset string) string {
+	return "count=250&offset=" + offset
+}
+
+func (limitOffsetPaginator) Next(offset string, results map[string]interface{}, itemCount int) (string, bool) {
+	if itemCount == 0 {
+		return "", false
+	}
+	cur, _ := strconv.Atoi(offset)
+	return strconv.Itoa(cur + itemCount), true
+}
+
+// oncePaginator is for endpoints that return everything in a single page.
+type oncePaginator struct{}
+
+func (oncePaginator) InitialOffset() string { return "0" }
+
+func (oncePaginator) Query(offset string) string {
+	return "count=250&offset=" + offset
+}
+
+func (oncePaginator) Next(offset string, results map[string]interface{}, itemCount int) (string, bool) {
+	return "", false
+}
+
+// cursorPaginator follows the CRM v3 "paging.next.after" convention.
+type cursorPaginator struct{}
+
+func (cursorPaginator) InitialOffset() string { return "" }
+
+func (cursorPaginator) Query(offset string) string {
+	if offset == "" {
+		return "limit=100"
+	}
+	return "limit=100&after=" + offset
+}
+
+func (cursorPaginator) Next(offset string, results map[string]interface{}, itemCount int) (string, bool) {
+	if itemCount == 0 {
+		return "", false
+	}
+	paging, ok := results["paging"].(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	next, ok := paging["next"].(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	after, ok := next["after"].(string)
+	if !ok || after == "" {
+		return "", false
+	}
+	return after, true
+}
+
+// offsetValue renders a response's raw offset field (Hubspot sends these as
+// either a JSON number or a numeric string, depending on the endpoint) back
+// into the string form Paginator deals in.
+func offsetValue(raw interface{}) string {
+	switch v := raw.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
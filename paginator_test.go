@@ -0,0 +1,87 @@
+package main
+
+import "testing"
+
+func TestHasMoreOffsetPaginatorNext(t *testing.T) {
+	cases := []struct {
+		name      string
+		results   map[string]interface{}
+		itemCount int
+		wantNext  string
+		wantMore  bool
+	}{
+		{"no items", map[string]interface{}{"has-more": true, "offset": "10"}, 0, "", false},
+		{"has-more false", map[string]interface{}{"has-more": false, "offset": "10"}, 5, "", false},
+		{"has-more missing", map[string]interface{}{}, 5, "", false},
+		{"has-more true, numeric offset", map[string]interface{}{"has-more": true, "offset": float64(10)}, 5, "10", true},
+		{"has-more true, string offset", map[string]interface{}{"has-more": true, "offset": "10"}, 5, "10", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			next, more := hasMoreOffsetPaginator{}.Next("0", c.results, c.itemCount)
+			if next != c.wantNext || more != c.wantMore {
+				t.Errorf("Next() = (%q, %v), want (%q, %v)", next, more, c.wantNext, c.wantMore)
+			}
+		})
+	}
+}
+
+func TestLimitOffsetPaginatorNext(t *testing.T) {
+	next, more := limitOffsetPaginator{}.Next("50", nil, 25)
+	if !more || next != "75" {
+		t.Errorf("Next() = (%q, %v), want (\"75\", true)", next, more)
+	}
+
+	next, more = limitOffsetPaginator{}.Next("50", nil, 0)
+	if more || next != "" {
+		t.Errorf("Next() on empty page = (%q, %v), want (\"\", false)", next, more)
+	}
+}
+
+func TestOncePaginatorNeverPages(t *testing.T) {
+	_, more := oncePaginator{}.Next("0", map[string]interface{}{"has-more": true}, 250)
+	if more {
+		t.Error("oncePaginator.Next() reported more pages, want it to always stop after one")
+	}
+}
+
+func TestCursorPaginatorNext(t *testing.T) {
+	cases := []struct {
+		name      string
+		results   map[string]interface{}
+		itemCount int
+		wantNext  string
+		wantMore  bool
+	}{
+		{"no items", map[string]interface{}{}, 0, "", false},
+		{"no paging field", map[string]interface{}{}, 10, "", false},
+		{"no next page", map[string]interface{}{
+			"paging": map[string]interface{}{},
+		}, 10, "", false},
+		{"empty after token", map[string]interface{}{
+			"paging": map[string]interface{}{"next": map[string]interface{}{"after": ""}},
+		}, 10, "", false},
+		{"has next page", map[string]interface{}{
+			"paging": map[string]interface{}{"next": map[string]interface{}{"after": "cursor-123"}},
+		}, 10, "cursor-123", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			next, more := cursorPaginator{}.Next("", c.results, c.itemCount)
+			if next != c.wantNext || more != c.wantMore {
+				t.Errorf("Next() = (%q, %v), want (%q, %v)", next, more, c.wantNext, c.wantMore)
+			}
+		})
+	}
+}
+
+func TestCursorPaginatorQuery(t *testing.T) {
+	if q := (cursorPaginator{}).Query(""); q != "limit=100" {
+		t.Errorf("Query(\"\") = %q, want %q", q, "limit=100")
+	}
+	if q := (cursorPaginator{}).Query("abc"); q != "limit=100&after=abc" {
+		t.Errorf("Query(\"abc\") = %q, want %q", q, "limit=100&after=abc")
+	}
+}
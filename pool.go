@@ -0,0 +1,51 @@
+package main
+
+import "sync"
+
+// Pool bounds how many Hubspot API workers run concurrently, so a backup
+// run can't spawn an unbounded number of goroutines against endpoints that
+// paginate deeply.
+//
+// Workers resubmit their own continuation/child jobs (a page resubmitting
+// itself for the next page, an endpoint fanning out one job per OnItem)
+// from inside the job currently occupying a slot. A literal bounded job
+// queue (a channel of fixed capacity, drained only by its own workers)
+// deadlocks the moment every worker is blocked trying to enqueue more work,
+// since nothing is left to drain it. Pool bounds concurrent *execution*
+// with a semaphore instead: Submit always returns immediately by spawning a
+// goroutine that waits on the semaphore, so there's no queue capacity to
+// fill and no self-submission deadlock. The tradeoff is that goroutines
+// queued on the semaphore are themselves unbounded in number (each one is
+// cheap and idle, just parked on a channel receive), where a true bounded
+// queue would instead push back on Submit once full.
+type Pool struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+// NewPool creates a pool that runs at most concurrency jobs at once.
+func NewPool(concurrency int) *Pool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Pool{sem: make(chan struct{}, concurrency)}
+}
+
+// Submit queues job to run as soon as a slot is free. It never blocks on
+// the pool being full, so it's always safe to call from within a job the
+// pool is currently running.
+func (p *Pool) Submit(job func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.sem <- struct{}{}
+		defer func() { <-p.sem }()
+		job()
+	}()
+}
+
+// Close blocks until every submitted job, including any it submitted in
+// turn, has run.
+func (p *Pool) Close() {
+	p.wg.Wait()
+}
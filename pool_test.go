@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestPoolRunsAllSubmittedJobs checks the basic contract: every submitted
+// job runs exactly once, and Close doesn't return until they all have.
+func TestPoolRunsAllSubmittedJobs(t *testing.T) {
+	p := NewPool(4)
+
+	var ran int32
+	for i := 0; i < 50; i++ {
+		p.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	p.Close()
+
+	if ran != 50 {
+		t.Errorf("ran = %d, want 50", ran)
+	}
+}
+
+// TestPoolBoundsConcurrency checks that at most concurrency jobs ever run
+// at once, even though Submit itself doesn't block.
+func TestPoolBoundsConcurrency(t *testing.T) {
+	const concurrency = 3
+	p := NewPool(concurrency)
+
+	var current, max int32
+	var mu sync.Mutex
+	for i := 0; i < 20; i++ {
+		p.Submit(func() {
+			n := atomic.AddInt32(&current, 1)
+			mu.Lock()
+			if n > max {
+				max = n
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&current, -1)
+		})
+	}
+	p.Close()
+
+	if max > concurrency {
+		t.Errorf("observed %d jobs running at once, want at most %d", max, concurrency)
+	}
+}
+
+// TestPoolSubmitFromWithinJob is a regression test for the deadlock chunk0-3
+// originally shipped with: a job resubmitting itself (or spawning child
+// jobs) back onto the pool from inside the job currently occupying a slot
+// must not block Close forever, even at concurrency 1.
+func TestPoolSubmitFromWithinJob(t *testing.T) {
+	p := NewPool(1)
+
+	var ran int32
+	var submit func(depth int)
+	submit = func(depth int) {
+		atomic.AddInt32(&ran, 1)
+		if depth > 0 {
+			p.Submit(func() { submit(depth - 1) })
+		}
+	}
+	p.Submit(func() { submit(10) })
+
+	done := make(chan struct{})
+	go func() {
+		p.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Pool.Close() deadlocked on a job resubmitting itself")
+	}
+
+	if ran != 11 {
+		t.Errorf("ran = %d, want 11", ran)
+	}
+}
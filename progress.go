@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+)
+
+// Progress renders the state of a backup run to the user. Piped/cron
+// invocations get structured JSON logs; interactive TTY runs get a live
+// progress bar instead, so scripted use doesn't have to scrape ANSI status
+// spam and interactive use doesn't have to read JSON.
+type Progress interface {
+	RunStarted()
+	Update(endpoint string, offset, count int)
+	Done(endpoint string, status string, duration time.Duration)
+	Error(endpoint string, message string)
+	RunFinished(path string)
+}
+
+func newProgress() Progress {
+	if isTerminal(os.Stdout) {
+		return newBarProgress()
+	}
+	return &logProgress{logger: slog.New(slog.NewJSONHandler(os.Stdout, nil))}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// logProgress emits one structured JSON line per event, suitable for
+// scheduled runs whose output gets scraped or shipped to a log aggregator.
+type logProgress struct {
+	logger *slog.Logger
+}
+
+func (p *logProgress) RunStarted() {
+	p.logger.Info("backup run started")
+}
+
+func (p *logProgress) Update(endpoint string, offset, count int) {
+	p.logger.Info("backing up", "endpoint", endpoint, "offset", offset, "count", count, "status", "in_progress")
+}
+
+func (p *logProgress) Done(endpoint string, status string, duration time.Duration) {
+	p.logger.Info("backed up", "endpoint", endpoint, "status", status, "duration_ms", duration.Milliseconds())
+}
+
+func (p *logProgress) Error(endpoint string, message string) {
+	p.logger.Error("backup error", "endpoint", endpoint, "status", "error", "message", message)
+}
+
+func (p *logProgress) RunFinished(path string) {
+	p.logger.Info("backup run finished", "path", path)
+}
+
+// barProgress renders a single live progress bar across every endpoint being
+// backed up concurrently, for interactive terminal use.
+type barProgress struct {
+	bar *pb.ProgressBar
+	mu  sync.Mutex
+}
+
+func newBarProgress() *barProgress {
+	bar := pb.New(0)
+	bar.SetTemplateString(`{{ "Backing up Hubspot:" }} {{counters . }} items {{ bar . }} {{speed . }}`)
+	bar.Start()
+	return &barProgress{bar: bar}
+}
+
+func (p *barProgress) RunStarted() {}
+
+func (p *barProgress) Update(endpoint string, offset, count int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bar.SetTotal(p.bar.Total() + int64(count))
+	p.bar.Add(count)
+}
+
+func (p *barProgress) Done(endpoint string, status string, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(os.Stderr, "\nBacked up all %v (%v)\n", endpoint, status)
+}
+
+func (p *barProgress) Error(endpoint string, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(os.Stderr, "\nError backing up %v: %v\n", endpoint, message)
+}
+
+func (p *barProgress) RunFinished(path string) {
+	p.bar.Finish()
+	fmt.Printf("\n############\nBackup saved in %v\n", path)
+}
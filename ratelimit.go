@@ -0,0 +1,45 @@
+package main
+
+import "time"
+
+// defaultRateLimit approximates Hubspot's private-app default cap of
+// roughly 100 requests per 10 seconds, leaving headroom for retries.
+const defaultRateLimit = 9
+
+// RateLimiter is a token-bucket limiter shared by every worker in a backup
+// run, so raising --concurrency can't push us over Hubspot's per-second and
+// per-10-second API caps.
+type RateLimiter struct {
+	tokens chan struct{}
+}
+
+func NewRateLimiter(ratePerSecond int) *RateLimiter {
+	if ratePerSecond < 1 {
+		ratePerSecond = 1
+	}
+
+	rl := &RateLimiter{tokens: make(chan struct{}, ratePerSecond)}
+	for i := 0; i < ratePerSecond; i++ {
+		rl.tokens <- struct{}{}
+	}
+
+	go rl.refill(ratePerSecond)
+	return rl
+}
+
+func (rl *RateLimiter) refill(ratePerSecond int) {
+	ticker := time.NewTicker(time.Second / time.Duration(ratePerSecond))
+	defer ticker.Stop()
+	for range ticker.C {
+		select {
+		case rl.tokens <- struct{}{}:
+		default:
+			// bucket already full
+		}
+	}
+}
+
+// Wait blocks until a token is available, consuming it.
+func (rl *RateLimiter) Wait() {
+	<-rl.tokens
+}
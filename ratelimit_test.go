@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRateLimiterStartsFull checks that a fresh RateLimiter can satisfy
+// ratePerSecond Wait()s immediately, without waiting for a refill tick.
+func TestRateLimiterStartsFull(t *testing.T) {
+	rl := NewRateLimiter(5)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			rl.Wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait() blocked before the initial token bucket was exhausted")
+	}
+}
+
+// TestNewRateLimiterClampsToOne checks the same floor NewPool applies:
+// a non-positive rate shouldn't produce a limiter that can never hand out
+// a token.
+func TestNewRateLimiterClampsToOne(t *testing.T) {
+	rl := NewRateLimiter(0)
+
+	done := make(chan struct{})
+	go func() {
+		rl.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait() never returned for a rate limiter constructed with ratePerSecond <= 0")
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Sink abstracts where backup output actually lands, so the same fetch/dedup
+// logic can write to the local disk or push straight to an S3-compatible
+// object store without a local staging step.
+type Sink interface {
+	Put(ctx context.Context, path string, data []byte) error
+	// PutStream is Put for data that's too large to hold in memory twice
+	// over (large file downloads): r is read once and written straight
+	// through. size is the number of bytes r will yield.
+	PutStream(ctx context.Context, path string, r io.Reader, size int64) error
+	// Get reads back data previously written with Put/PutStream, so
+	// manifest loading (see loadManifest) works the same regardless of
+	// which sink a run was written through.
+	Get(path string) ([]byte, error)
+	Exists(path string) (bool, error)
+	List(prefix string) ([]string, error)
+}
+
+var (
+	flagSink        = flag.String("sink", "local", "Where to write backup output: local or s3")
+	flagS3Endpoint  = flag.String("s3-endpoint", "", "S3/MinIO/GCS endpoint, e.g. s3.amazonaws.com")
+	flagS3Bucket    = flag.String("s3-bucket", "", "S3/MinIO/GCS bucket to write the backup into")
+	flagS3AccessKey = flag.String("s3-access-key", "", "S3/MinIO/GCS access key")
+	flagS3SecretKey = flag.String("s3-secret-key", "", "S3/MinIO/GCS secret key")
+	flagS3UseSSL    = flag.Bool("s3-use-ssl", true, "Use HTTPS when talking to the S3/MinIO/GCS endpoint")
+)
+
+// buildSink turns the --sink flags into a concrete Sink. Call it after
+// flag.Parse() has run.
+func buildSink() (Sink, error) {
+	switch strings.ToLower(*flagSink) {
+	case "", "local":
+		return &LocalSink{}, nil
+	case "s3":
+		if *flagS3Endpoint == "" || *flagS3Bucket == "" {
+			return nil, fmt.Errorf("--sink=s3 requires --s3-endpoint and --s3-bucket")
+		}
+		client, err := minio.New(*flagS3Endpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(*flagS3AccessKey, *flagS3SecretKey, ""),
+			Secure: *flagS3UseSSL,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating s3 client: %w", err)
+		}
+		return &S3Sink{client: client, bucket: *flagS3Bucket}, nil
+	default:
+		return nil, fmt.Errorf("unknown --sink %q, want local or s3", *flagSink)
+	}
+}
+
+// LocalSink writes backup output to the local filesystem, relative to the
+// current working directory, exactly as the tool always has.
+type LocalSink struct{}
+
+func (s *LocalSink) Put(ctx context.Context, path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+func (s *LocalSink) PutStream(ctx context.Context, path string, r io.Reader, size int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func (s *LocalSink) Get(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+func (s *LocalSink) Exists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *LocalSink) List(prefix string) ([]string, error) {
+	var names []string
+	err := filepath.Walk(prefix, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			names = append(names, path)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// S3Sink writes backup output to an S3-compatible object store (AWS S3,
+// MinIO, GCS's S3 interop endpoint, ...).
+type S3Sink struct {
+	client *minio.Client
+	bucket string
+}
+
+func (s *S3Sink) Put(ctx context.Context, path string, data []byte) error {
+	_, err := s.client.PutObject(ctx, s.bucket, path, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/json",
+	})
+	return err
+}
+
+func (s *S3Sink) PutStream(ctx context.Context, path string, r io.Reader, size int64) error {
+	_, err := s.client.PutObject(ctx, s.bucket, path, r, size, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	return err
+}
+
+func (s *S3Sink) Get(path string) ([]byte, error) {
+	obj, err := s.client.GetObject(context.Background(), s.bucket, path, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return ioutil.ReadAll(obj)
+}
+
+func (s *S3Sink) Exists(path string) (bool, error) {
+	_, err := s.client.StatObject(context.Background(), s.bucket, path, minio.StatObjectOptions{})
+	if err == nil {
+		return true, nil
+	}
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *S3Sink) List(prefix string) ([]string, error) {
+	var names []string
+	for obj := range s.client.ListObjects(context.Background(), s.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		names = append(names, obj.Key)
+	}
+	return names, nil
+}